@@ -0,0 +1,185 @@
+// Copyright (c) 2017-2026 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	apiv3 "github.com/projectcalico/api/pkg/apis/projectcalico/v3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/projectcalico/calico/libcalico-go/lib/backend/model"
+)
+
+const (
+	BGPPeerResourceName = "BGPPeers"
+	BGPPeerCRDName      = "bgppeers.crd.projectcalico.org"
+
+	// BGPPeerSecretRefsAnnotation stores a stable, non-sensitive description of the
+	// secret-backed fields on a BGPPeer (currently just Spec.Password.SecretKeyRef),
+	// so that the reference survives round-tripping through the CRD backend even for
+	// callers that never resolve it to plaintext. It never carries the plaintext
+	// value itself.
+	BGPPeerSecretRefsAnnotation = "projectcalico.org/secret-refs"
+)
+
+// NewBGPPeerClient creates a new client for interacting with BGPPeer resources.
+func NewBGPPeerClient(c kubernetes.Interface, r rest.Interface) K8sResourceClient {
+	return NewBGPPeerClientWithOptions(c, r, ClientOptions{})
+}
+
+// NewBGPPeerClientWithOptions creates a new client for interacting with BGPPeer resources,
+// with caller-specified options such as extra owned metadata prefixes or a projection mode.
+func NewBGPPeerClientWithOptions(c kubernetes.Interface, r rest.Interface, opts ClientOptions) K8sResourceClient {
+	return newCustomK8sResourceClient(
+		r,
+		bgpPeerConverter{},
+		"BGPPeer",
+		BGPPeerResourceName,
+		"BGPPeer",
+		false,
+		reflect.TypeOf(apiv3.BGPPeer{}),
+		reflect.TypeOf(apiv3.BGPPeerList{}),
+		apiv3.KindBGPPeer,
+		"crd.projectcalico.org/v1",
+		opts,
+	)
+}
+
+// bgpPeerConverter implements K8sResourceConverter for the BGPPeer resource.
+type bgpPeerConverter struct{}
+
+func (_ bgpPeerConverter) ListInterfaceToKey(l model.ListInterface) model.Key {
+	pl := l.(model.ResourceListOptions)
+	if pl.Name != "" {
+		return model.ResourceKey{Name: pl.Name, Kind: apiv3.KindBGPPeer}
+	}
+	return nil
+}
+
+func (_ bgpPeerConverter) KeyToName(k model.Key) (string, error) {
+	rk, ok := k.(model.ResourceKey)
+	if !ok {
+		return "", fmt.Errorf("key is not a valid ResourceKey: %v", k)
+	}
+	return rk.Name, nil
+}
+
+func (_ bgpPeerConverter) NameToKey(name string) (model.Key, error) {
+	return model.ResourceKey{Name: name, Kind: apiv3.KindBGPPeer}, nil
+}
+
+func (c bgpPeerConverter) ToKVPair(r Resource) (*model.KVPair, error) {
+	peer := r.(*apiv3.BGPPeer)
+	return &model.KVPair{
+		Key: model.ResourceKey{
+			Name: peer.Name,
+			Kind: apiv3.KindBGPPeer,
+		},
+		Value:    peer,
+		Revision: peer.ResourceVersion,
+	}, nil
+}
+
+func (c bgpPeerConverter) FromKVPair(kvp *model.KVPair) (Resource, error) {
+	peer, ok := kvp.Value.(*apiv3.BGPPeer)
+	if !ok {
+		return nil, fmt.Errorf("value is not a valid *apiv3.BGPPeer: %v", kvp.Value)
+	}
+	return peer, nil
+}
+
+// AnnotateBGPPeerSecretRef stamps BGPPeerSecretRefsAnnotation on peer from
+// peer.Spec.Password.SecretKeyRef, if set. It is called as part of
+// ConvertCalicoResourceToK8sResource so that the reference to where the password lives
+// survives being written to (and read back from) the CRD backend, without ever writing
+// the plaintext password itself.
+func AnnotateBGPPeerSecretRef(peer *apiv3.BGPPeer) {
+	ref := peer.Spec.Password
+	if ref == nil || ref.SecretKeyRef == nil {
+		return
+	}
+	if peer.ObjectMeta.Annotations == nil {
+		peer.ObjectMeta.Annotations = map[string]string{}
+	}
+	peer.ObjectMeta.Annotations[BGPPeerSecretRefsAnnotation] = fmt.Sprintf("password=%s/%s", ref.SecretKeyRef.Name, ref.SecretKeyRef.Key)
+}
+
+// RestoreBGPPeerSecretRef reconstructs peer.Spec.Password.SecretKeyRef from
+// BGPPeerSecretRefsAnnotation when the spec doesn't already carry it. This is belt and
+// braces: the CRD backend persists Spec in full, so the annotation is normally
+// redundant, but it guards against a caller that only round-trips ObjectMeta (e.g. a
+// metadata-only projection) ever losing the reference.
+func RestoreBGPPeerSecretRef(peer *apiv3.BGPPeer) {
+	if peer.Spec.Password != nil && peer.Spec.Password.SecretKeyRef != nil {
+		return
+	}
+	raw, ok := peer.ObjectMeta.Annotations[BGPPeerSecretRefsAnnotation]
+	if !ok {
+		return
+	}
+	raw = strings.TrimPrefix(raw, "password=")
+	parts := strings.SplitN(raw, "/", 2)
+	if len(parts) != 2 {
+		return
+	}
+	peer.Spec.Password = &apiv3.BGPPassword{
+		SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: parts[0]},
+			Key:                  parts[1],
+		},
+	}
+}
+
+// ResolveBGPPeerPassword resolves peer.Spec.Password.SecretKeyRef (if set) against the
+// given SecretGetter and returns its plaintext value. The Calico v3 API never carries a
+// plaintext password field, so the result is returned directly rather than written back
+// onto peer; callers are expected to use it out of band (e.g. when rendering a BGP config
+// file) via their own explicit call, never by persisting it.
+func ResolveBGPPeerPassword(ctx context.Context, peer *apiv3.BGPPeer, getter SecretGetter) (string, error) {
+	if peer.Spec.Password == nil || peer.Spec.Password.SecretKeyRef == nil {
+		return "", nil
+	}
+	if getter == nil {
+		return "", fmt.Errorf("no SecretGetter configured to resolve BGPPeer %s password", peer.Name)
+	}
+	ref := peer.Spec.Password.SecretKeyRef
+	secret, err := getter.Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	value, ok := secret.Data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no key %s", ref.Name, ref.Key)
+	}
+	return string(value), nil
+}
+
+// GetResolvedPassword is a convenience for callers of a BGPPeer client configured with a
+// ClientOptions.SecretGetter: it looks up the plaintext password for the BGPPeer in the
+// given KVPair, as returned by Get/List, using the client's configured SecretGetter.
+func (c *customK8sResourceClient) GetResolvedPassword(ctx context.Context, kvp *model.KVPair) (string, error) {
+	peer, ok := kvp.Value.(*apiv3.BGPPeer)
+	if !ok {
+		return "", fmt.Errorf("value is not a valid *apiv3.BGPPeer: %v", kvp.Value)
+	}
+	return ResolveBGPPeerPassword(ctx, peer, c.options.SecretGetter)
+}
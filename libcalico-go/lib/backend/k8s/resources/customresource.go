@@ -0,0 +1,792 @@
+// Copyright (c) 2017-2026 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	apiv3 "github.com/projectcalico/api/pkg/apis/projectcalico/v3"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+
+	"github.com/projectcalico/calico/libcalico-go/lib/backend/api"
+	"github.com/projectcalico/calico/libcalico-go/lib/backend/model"
+	cerrors "github.com/projectcalico/calico/libcalico-go/lib/errors"
+)
+
+// Resource is the common interface implemented by all of the Calico v3 types that are
+// backed by a Kubernetes CRD. It allows the generic conversion helpers in this file to
+// operate on any of them without needing resource-specific type assertions.
+type Resource interface {
+	runtime.Object
+	metav1.ObjectMetaAccessor
+}
+
+// ResourceList is the list equivalent of Resource.
+type ResourceList interface {
+	runtime.Object
+}
+
+// ownedMetadataPrefixes are the label/annotation key prefixes that this client considers
+// "ours" when round-tripping a Calico resource through the Kubernetes API. Anything under
+// one of these prefixes is preserved across conversion; everything else is stripped from
+// the Kubernetes-side labels/annotations (but is retained, verbatim, on the Calico side via
+// the v3 object's own metadata).
+//
+// projectcalico.org/ and operator.tigera.io/ are owned unconditionally. Callers embedding
+// this library (third-party controllers, operators) can register additional prefixes of
+// their own via RegisterOwnedMetadataPrefix so that their labels/annotations survive
+// round-tripping too.
+var ownedMetadataPrefixes = []string{
+	"projectcalico.org/",
+	"operator.tigera.io/",
+}
+
+// RegisterOwnedMetadataPrefix registers an additional label/annotation key prefix that
+// should be treated as "owned" by conversion between the Calico v3 API and the Kubernetes
+// CRD backend, in addition to the built-in projectcalico.org/ and operator.tigera.io/
+// prefixes. Labels and annotations under a registered prefix are preserved on the
+// Kubernetes resource when converting from a Calico resource; anything else is stripped.
+//
+// This is intended for operators and third-party controllers that embed libcalico-go and
+// need their own labels/annotations (e.g. "example.com/") to survive round-tripping
+// through the CRD backend. It is not safe to call concurrently with conversions; register
+// prefixes during process initialization, before any clients are used.
+func RegisterOwnedMetadataPrefix(prefix string) {
+	for _, p := range ownedMetadataPrefixes {
+		if p == prefix {
+			return
+		}
+	}
+	ownedMetadataPrefixes = append(ownedMetadataPrefixes, prefix)
+}
+
+// isOwnedMetadataKey returns true if the given label/annotation key is owned by this
+// client (and so should be preserved on the Kubernetes resource), taking into account
+// both the built-in prefixes and any extra prefixes registered by the caller via
+// RegisterOwnedMetadataPrefix or configured on the client via ClientOptions.
+func isOwnedMetadataKey(key string, extraPrefixes []string) bool {
+	for _, prefix := range ownedMetadataPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	for _, prefix := range extraPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// K8sResourceConverter converts between the Calico v3 API representation of a resource
+// and the model.KVPair representation used by the backend API, for a single resource
+// kind (e.g. BGPPeer).
+type K8sResourceConverter interface {
+	// ListInterfaceToKey converts a ListInterface to a Key if the ListInterface is
+	// already fully qualified for a specific resource. Returns nil if the
+	// ListInterface does not identify a single resource.
+	ListInterfaceToKey(l model.ListInterface) model.Key
+
+	// KeyToName converts a Key to the name of the Kubernetes resource.
+	KeyToName(k model.Key) (string, error)
+
+	// NameToKey converts the name of the Kubernetes resource to a Key.
+	NameToKey(name string) (model.Key, error)
+
+	// ToKVPair converts the Kubernetes resource into a KVPair.
+	ToKVPair(r Resource) (*model.KVPair, error)
+
+	// FromKVPair converts a KVPair to the Kubernetes resource.
+	FromKVPair(kvp *model.KVPair) (Resource, error)
+}
+
+// Projection controls what a customK8sResourceClient returns from Get, List and Watch.
+type Projection int
+
+const (
+	// ProjectFull decodes the full Calico spec, as well as metadata. This is the
+	// default, pre-existing behaviour.
+	ProjectFull Projection = iota
+
+	// ProjectMetadataOnly returns KVPairs whose Value is a *metav1.PartialObjectMetadata
+	// and skips ConvertK8sResourceToCalicoResource. The full resource, spec included,
+	// is still decoded from the API response; the spec is just not converted or
+	// returned. This suits callers that only need to reconcile on name/UID/labels,
+	// e.g. Typha, the operator, or GC loops.
+	ProjectMetadataOnly
+)
+
+// ClientOptions configures optional behaviour of a customK8sResourceClient beyond the
+// plain CRUD path. The zero value selects the original, pre-existing behaviour.
+type ClientOptions struct {
+	// OwnedMetadataPrefixes are additional label/annotation prefixes (beyond the
+	// package-wide defaults registered via RegisterOwnedMetadataPrefix) that this
+	// particular client should treat as owned.
+	OwnedMetadataPrefixes []string
+
+	// Projection controls whether Get/List/Watch return the full resource (ProjectFull,
+	// the default) or only its metadata (ProjectMetadataOnly).
+	Projection Projection
+
+	// SecretGetter, if set, lets a caller resolve a secret-backed field (e.g.
+	// BGPPeerSpec.Password.SecretKeyRef) to its plaintext value via a method such as
+	// GetResolvedPassword. Resolution is always an explicit, out-of-band call -- it
+	// never happens implicitly as part of Get/List/Watch, since the plaintext must
+	// never be persisted back into a Calico resource. corev1.SecretInterface
+	// satisfies this interface.
+	SecretGetter SecretGetter
+
+	// ClusterID identifies the cluster this client is writing resources *from*. When
+	// set, resources written through this client are stamped with the reserved
+	// source-cluster/source-uid/source-resource-version annotations (see
+	// stampSourceClusterAnnotations) so that a resource replicated into another
+	// cluster keeps a stable, reconstructible identity there.
+	ClusterID string
+}
+
+// Reserved annotations used to track the original identity of a Calico resource that has
+// been replicated from one cluster into another. SourceUIDAnnotation in particular lets
+// ConvertK8sResourceToCalicoResource reconstruct a deterministic types.UID on read, even
+// though the local Kubernetes API server assigns its own UID on write.
+const (
+	SourceClusterAnnotation         = "projectcalico.org/source-cluster"
+	SourceUIDAnnotation             = "projectcalico.org/source-uid"
+	SourceResourceVersionAnnotation = "projectcalico.org/source-resource-version"
+)
+
+// stampSourceClusterAnnotations records om's current UID/ResourceVersion, and clusterID,
+// under the reserved source-* annotations, so that identity survives a subsequent write
+// (by a different client, in a different cluster) assigning its own local UID. It is a
+// no-op if clusterID is empty, or if the resource already carries a source-cluster
+// annotation (so that re-replicating an already-replicated resource doesn't overwrite its
+// original source with the intermediate hop).
+func stampSourceClusterAnnotations(om *metav1.ObjectMeta, clusterID string) {
+	if clusterID == "" {
+		return
+	}
+	if _, ok := om.Annotations[SourceClusterAnnotation]; ok {
+		return
+	}
+	if om.Annotations == nil {
+		om.Annotations = map[string]string{}
+	}
+	om.Annotations[SourceClusterAnnotation] = clusterID
+	om.Annotations[SourceUIDAnnotation] = string(om.UID)
+	om.Annotations[SourceResourceVersionAnnotation] = om.ResourceVersion
+}
+
+// reconstructSourceUID overwrites om.UID with the value recorded in SourceUIDAnnotation,
+// if present, so that a resource replicated from another cluster keeps a deterministic
+// identity even though this cluster's API server assigned it a different local UID.
+func reconstructSourceUID(om *metav1.ObjectMeta) {
+	if uid, ok := om.Annotations[SourceUIDAnnotation]; ok && uid != "" {
+		om.UID = types.UID(uid)
+	}
+}
+
+// legacyUIDXORMask translates between the UID a Kubernetes apiserver assigns a CRD-backed
+// object and the UID Calico's v3 API exposes for it. It predates the Kubernetes Datastore
+// Driver: Calico's original etcd datastore computed resource UIDs deterministically, and
+// resources migrated onto the KDD backend still need their externally-visible v3 UID to
+// agree with what that datastore produced, even though the apiserver now assigns its own.
+// XOR is self-inverse, so the same mask converts a UID in either direction.
+var legacyUIDXORMask = [16]byte{
+	0xc3, 0x18, 0xe7, 0xa5, 0xbd, 0x00, 0x00, 0x42,
+	0x00, 0xe7, 0x5a, 0x99, 0x3c, 0xbd, 0x99, 0xdb,
+}
+
+// convertUIDCompat translates uid between its Kubernetes apiserver-assigned form and its
+// Calico v3 API-facing form (see legacyUIDXORMask). It is a no-op for anything that isn't
+// a well-formed UUID -- e.g. the empty UID on a not-yet-created object -- returning uid
+// unchanged.
+func convertUIDCompat(uid types.UID) types.UID {
+	raw, err := uuidToBytes(string(uid))
+	if err != nil {
+		return uid
+	}
+	for i := range raw {
+		raw[i] ^= legacyUIDXORMask[i]
+	}
+	return types.UID(bytesToUUID(raw))
+}
+
+// uuidToBytes parses the canonical 8-4-4-4-12 hyphenated hex form of a UUID into its raw
+// 16 bytes.
+func uuidToBytes(s string) ([16]byte, error) {
+	var out [16]byte
+	hexDigits := strings.ReplaceAll(s, "-", "")
+	if len(hexDigits) != 32 {
+		return out, fmt.Errorf("not a UUID: %q", s)
+	}
+	decoded, err := hex.DecodeString(hexDigits)
+	if err != nil {
+		return out, err
+	}
+	copy(out[:], decoded)
+	return out, nil
+}
+
+// bytesToUUID is the inverse of uuidToBytes.
+func bytesToUUID(b [16]byte) string {
+	h := hex.EncodeToString(b[:])
+	return fmt.Sprintf("%s-%s-%s-%s-%s", h[0:8], h[8:12], h[12:16], h[16:20], h[20:32])
+}
+
+// SecretGetter is the minimal interface this package needs in order to resolve a
+// SecretKeyRef into its plaintext value. It is satisfied by a real
+// corev1.SecretInterface as well as by fakes in tests.
+type SecretGetter interface {
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*corev1.Secret, error)
+}
+
+// K8sResourceClient is the interface implemented by every generated per-resource client
+// in this package (e.g. the client returned by NewBGPPeerClient). It mirrors the backend
+// api.Client interface but is scoped to a single resource kind.
+type K8sResourceClient interface {
+	Create(ctx context.Context, kvp *model.KVPair) (*model.KVPair, error)
+	Update(ctx context.Context, kvp *model.KVPair) (*model.KVPair, error)
+	Delete(ctx context.Context, key model.Key, revision string, uid *string) (*model.KVPair, error)
+	Get(ctx context.Context, key model.Key, revision string) (*model.KVPair, error)
+	List(ctx context.Context, list model.ListInterface, revision string) (*model.KVPairList, error)
+	Watch(ctx context.Context, list model.ListInterface, revision string) (api.WatchInterface, error)
+	// Patch applies a Kubernetes patch (JSON patch, merge patch, or server-side
+	// apply) to the resource identified by key. For a server-side apply it forwards
+	// fieldManager/force through to the underlying REST client.
+	Patch(ctx context.Context, key model.Key, pt types.PatchType, data []byte, opts metav1.PatchOptions) (*model.KVPair, error)
+	// Apply performs a server-side apply of kvp's resource, using fieldManager as
+	// the field manager and force to resolve conflicting field ownership.
+	Apply(ctx context.Context, kvp *model.KVPair, fieldManager string, force bool) (*model.KVPair, error)
+	EnsureInitialized() error
+}
+
+// customK8sResourceClient implements the resources.K8sResourceClient interface and
+// provides generic support for a Calico v3 resource that is backed 1:1 by a Kubernetes
+// custom resource (CRD).
+type customK8sResourceClient struct {
+	restClient      rest.Interface
+	converter       K8sResourceConverter
+	name            string
+	resource        string
+	description     string
+	k8sResourceType reflect.Type
+	k8sListType     reflect.Type
+	namespaced      bool
+
+	// kind and groupVersion identify this client's resource kind for TypeMeta
+	// purposes -- in particular, a server-side apply body must carry them since the
+	// apiserver uses TypeMeta, not the REST path, to identify what's being applied.
+	kind         string
+	groupVersion schema.GroupVersion
+
+	// options holds the optional, per-client behaviour configured via
+	// NewBGPPeerClientWithOptions (and equivalents for other resource kinds).
+	options ClientOptions
+}
+
+// newCustomK8sResourceClient creates a customK8sResourceClient for a particular resource
+// kind. It is called by the per-resource constructors (e.g. NewBGPPeerClient) rather than
+// being used directly.
+func newCustomK8sResourceClient(restClient rest.Interface, converter K8sResourceConverter, name, resource, description string, namespaced bool, k8sResourceType, k8sListType reflect.Type, kind, apiVersion string, opts ClientOptions) *customK8sResourceClient {
+	gv, _ := schema.ParseGroupVersion(apiVersion)
+	return &customK8sResourceClient{
+		restClient:      restClient,
+		converter:       converter,
+		name:            name,
+		resource:        resource,
+		description:     description,
+		namespaced:      namespaced,
+		k8sResourceType: k8sResourceType,
+		k8sListType:     k8sListType,
+		kind:            kind,
+		groupVersion:    gv,
+		options:         opts,
+	}
+}
+
+func (c *customK8sResourceClient) listInterfaceToKey(l model.ListInterface) model.Key {
+	return c.converter.ListInterfaceToKey(l)
+}
+
+func (c *customK8sResourceClient) keyToName(k model.Key) (string, error) {
+	return c.converter.KeyToName(k)
+}
+
+func (c *customK8sResourceClient) nameToKey(name string) (model.Key, error) {
+	return c.converter.NameToKey(name)
+}
+
+// convertResourceToKVPair converts the supplied Kubernetes resource into the equivalent
+// backend KVPair. Its labels/annotations are passed through as they are.
+func (c *customK8sResourceClient) convertResourceToKVPair(r Resource) (*model.KVPair, error) {
+	return c.converter.ToKVPair(r)
+}
+
+// convertKVPairToResource converts the supplied KVPair into the equivalent Kubernetes
+// resource, ready to be written through the Kubernetes API.
+func (c *customK8sResourceClient) convertKVPairToResource(kvp *model.KVPair) (Resource, error) {
+	return c.converter.FromKVPair(kvp)
+}
+
+// convertResourceToKVPairProjected is like convertResourceToKVPair, but honours the
+// client's configured Projection: when the client is in ProjectMetadataOnly mode, it
+// skips the (comparatively expensive) spec conversion and returns a KVPair wrapping a
+// *metav1.PartialObjectMetadata instead of the full Calico resource. Either way, the
+// returned labels/annotations are Kubernetes' own, unsanitized -- a metadata-only
+// List/Watch returns the same metadata a full one would.
+func (c *customK8sResourceClient) convertResourceToKVPairProjected(r Resource) (*model.KVPair, error) {
+	if c.options.Projection == ProjectMetadataOnly {
+		return convertKVPairToPartialMetadata(r)
+	}
+	return c.convertResourceToKVPair(r)
+}
+
+// convertKVPairToPartialMetadata converts the given Kubernetes resource into a KVPair
+// whose Value is a *metav1.PartialObjectMetadata: only TypeMeta and ObjectMeta are
+// copied into it; the spec, already decoded into r, is not converted or returned. Labels/annotations are returned exactly as
+// Kubernetes has them -- unlike the write path, the full read path (via
+// ConvertK8sResourceToCalicoResource) never strips anything either, since Kubernetes is
+// the source of truth for metadata it already carries, and a metadata-only List/Watch must
+// see the same labels/annotations a full one would. UID conversion and source-UID
+// reconstruction, which the full path gets from ConvertK8sResourceToCalicoResource, are
+// applied here explicitly since
+// this path bypasses that function entirely -- so a metadata-only client (e.g. a GC loop)
+// still sees the same stable, replicated identity as a full client does.
+func convertKVPairToPartialMetadata(r Resource) (*model.KVPair, error) {
+	om := r.GetObjectMeta()
+	meta, ok := om.(*metav1.ObjectMeta)
+	if !ok {
+		return nil, fmt.Errorf("unexpected ObjectMeta implementation %T", om)
+	}
+	meta.UID = convertUIDCompat(meta.UID)
+	reconstructSourceUID(meta)
+
+	partial := &metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{Kind: r.GetObjectKind().GroupVersionKind().Kind},
+		ObjectMeta: *meta.DeepCopy(),
+	}
+
+	return &model.KVPair{
+		Key: model.ResourceKey{
+			Name: partial.Name,
+			Kind: partial.TypeMeta.Kind,
+		},
+		Value:    partial,
+		Revision: partial.ResourceVersion,
+	}, nil
+}
+
+// sanitizeMetadataForK8s strips any labels/annotations that are not owned by this client
+// (see isOwnedMetadataKey) from the given ObjectMeta, in place. Everything else on the
+// ObjectMeta -- including ManagedFields -- passes through untouched, so server-side-apply
+// field ownership set by tools like Crossplane or Flux survives round-tripping through
+// this conversion.
+func (c *customK8sResourceClient) sanitizeMetadataForK8s(om *metav1.ObjectMeta) {
+	sanitizeOwnedMetadata(om, c.options.OwnedMetadataPrefixes)
+}
+
+func sanitizeOwnedMetadata(om *metav1.ObjectMeta, extraPrefixes []string) {
+	for k := range om.Labels {
+		if !isOwnedMetadataKey(k, extraPrefixes) {
+			delete(om.Labels, k)
+		}
+	}
+	for k := range om.Annotations {
+		if !isOwnedMetadataKey(k, extraPrefixes) {
+			delete(om.Annotations, k)
+		}
+	}
+}
+
+// ConvertCalicoResourceToK8sResource converts the given Calico v3 resource into the form
+// that should be written to the Kubernetes API: labels and annotations that are not owned
+// by Calico (or a caller-registered prefix) are stripped so that round-tripping through
+// the CRD backend doesn't leak them into, or clobber them in, the Kubernetes object.
+func ConvertCalicoResourceToK8sResource(r Resource) (Resource, error) {
+	return convertCalicoResourceToK8sResource(r, nil)
+}
+
+func convertCalicoResourceToK8sResource(r Resource, extraPrefixes []string) (Resource, error) {
+	return convertCalicoResourceToK8sResourceForCluster(r, extraPrefixes, "")
+}
+
+func convertCalicoResourceToK8sResourceForCluster(r Resource, extraPrefixes []string, clusterID string) (Resource, error) {
+	om := r.GetObjectMeta()
+	meta, ok := om.(*metav1.ObjectMeta)
+	if !ok {
+		return nil, fmt.Errorf("unexpected ObjectMeta implementation %T", om)
+	}
+	if peer, ok := r.(*apiv3.BGPPeer); ok {
+		AnnotateBGPPeerSecretRef(peer)
+	}
+	// Stamp before converting the UID, so that source-uid holds the v3 UID that
+	// reconstructSourceUID hands back on read.
+	stampSourceClusterAnnotations(meta, clusterID)
+	meta.UID = convertUIDCompat(meta.UID)
+	sanitizeOwnedMetadata(meta, extraPrefixes)
+	return r, nil
+}
+
+// ConvertK8sResourceToCalicoResource converts the given Kubernetes-backed resource, as
+// read back from the Kubernetes API, into the equivalent Calico v3 resource. This is the
+// inverse of ConvertCalicoResourceToK8sResource; it does not need to strip anything since
+// the Kubernetes object is the source of truth for the labels/annotations it already
+// carries.
+func ConvertK8sResourceToCalicoResource(r Resource) error {
+	return convertK8sResourceToCalicoResource(r, nil)
+}
+
+func convertK8sResourceToCalicoResource(r Resource, extraPrefixes []string) error {
+	// The Kubernetes resource's labels/annotations are already the complete set of
+	// Calico-owned and user-supplied metadata; there is nothing to strip on this
+	// direction. Resource-specific restoration (e.g. reconstructing a BGPPeer's
+	// secret reference from its annotation) still runs here so that this function
+	// remains the single entry point for "just read this back from Kubernetes".
+	log.Debugf("Converting k8s resource %s/%s to Calico resource", r.GetObjectMeta().GetNamespace(), r.GetObjectMeta().GetName())
+	if peer, ok := r.(*apiv3.BGPPeer); ok {
+		RestoreBGPPeerSecretRef(peer)
+	}
+	om := r.GetObjectMeta()
+	if meta, ok := om.(*metav1.ObjectMeta); ok {
+		meta.UID = convertUIDCompat(meta.UID)
+		reconstructSourceUID(meta)
+	}
+	return nil
+}
+
+// The methods below implement the generic CRUD operations common to every CRD-backed
+// resource. Resource-specific behaviour is supplied entirely by the K8sResourceConverter.
+
+func (c *customK8sResourceClient) Create(ctx context.Context, kvp *model.KVPair) (*model.KVPair, error) {
+	r, err := c.convertKVPairToResource(kvp)
+	if err != nil {
+		return nil, err
+	}
+	r, err = convertCalicoResourceToK8sResourceForCluster(r, c.options.OwnedMetadataPrefixes, c.options.ClusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := reflect.New(c.k8sResourceType).Interface().(Resource)
+	err = c.restClient.Post().
+		Resource(c.resource).
+		Body(r).
+		Do(ctx).
+		Into(out)
+	if err != nil {
+		return nil, K8sErrorToCalico(err, kvp.Key)
+	}
+	return c.convertResourceToKVPair(out)
+}
+
+func (c *customK8sResourceClient) Update(ctx context.Context, kvp *model.KVPair) (*model.KVPair, error) {
+	r, err := c.convertKVPairToResource(kvp)
+	if err != nil {
+		return nil, err
+	}
+	r, err = convertCalicoResourceToK8sResourceForCluster(r, c.options.OwnedMetadataPrefixes, c.options.ClusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := c.keyToName(kvp.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	out := reflect.New(c.k8sResourceType).Interface().(Resource)
+	err = c.restClient.Put().
+		Resource(c.resource).
+		Name(name).
+		Body(r).
+		Do(ctx).
+		Into(out)
+	if err != nil {
+		return nil, K8sErrorToCalico(err, kvp.Key)
+	}
+	return c.convertResourceToKVPair(out)
+}
+
+// Patch applies pt (e.g. types.ApplyPatchType for server-side apply) to the resource
+// identified by key, with data as the patch body. For types.ApplyPatchType,
+// opts.FieldManager and opts.Force are forwarded to the underlying REST client so that
+// server-side-apply semantics (field ownership, conflict resolution) are preserved end to
+// end; for any other patch type they are not sent.
+func (c *customK8sResourceClient) Patch(ctx context.Context, key model.Key, pt types.PatchType, data []byte, opts metav1.PatchOptions) (*model.KVPair, error) {
+	name, err := c.keyToName(key)
+	if err != nil {
+		return nil, err
+	}
+
+	req := c.restClient.Patch(pt).
+		Resource(c.resource).
+		Name(name).
+		Body(data)
+
+	// The apiserver's ValidatePatchOptions rejects fieldManager/force for anything
+	// other than ApplyPatchType ("may not be specified for non-apply patch"), so
+	// only forward them for a server-side apply.
+	if pt == types.ApplyPatchType {
+		force := opts.Force != nil && *opts.Force
+		req = req.Param("fieldManager", opts.FieldManager).Param("force", strconv.FormatBool(force))
+	}
+
+	out := reflect.New(c.k8sResourceType).Interface().(Resource)
+	err = req.Do(ctx).Into(out)
+	if err != nil {
+		return nil, K8sErrorToCalico(err, key)
+	}
+	if err := ConvertK8sResourceToCalicoResource(out); err != nil {
+		return nil, err
+	}
+	return c.convertResourceToKVPair(out)
+}
+
+// Apply performs a server-side apply of kvp's resource as fieldManager, forcing
+// conflicting field ownership to be taken over if force is true.
+func (c *customK8sResourceClient) Apply(ctx context.Context, kvp *model.KVPair, fieldManager string, force bool) (*model.KVPair, error) {
+	r, err := c.convertKVPairToResource(kvp)
+	if err != nil {
+		return nil, err
+	}
+	r, err = convertCalicoResourceToK8sResourceForCluster(r, c.options.OwnedMetadataPrefixes, c.options.ClusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A server-side apply body must never carry managedFields -- the apiserver
+	// rejects an apply request whose metadata.managedFields is non-nil -- and must
+	// carry TypeMeta, which apiserver uses (in lieu of a REST path) to identify the
+	// resource being applied.
+	om := r.GetObjectMeta()
+	meta, ok := om.(*metav1.ObjectMeta)
+	if !ok {
+		return nil, fmt.Errorf("unexpected ObjectMeta implementation %T", om)
+	}
+	meta.ManagedFields = nil
+	r.GetObjectKind().SetGroupVersionKind(c.groupVersion.WithKind(c.kind))
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Patch(ctx, kvp.Key, types.ApplyPatchType, data, metav1.PatchOptions{FieldManager: fieldManager, Force: &force})
+}
+
+func (c *customK8sResourceClient) Delete(ctx context.Context, key model.Key, revision string, uid *string) (*model.KVPair, error) {
+	kvp, err := c.Get(ctx, key, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := c.keyToName(key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.restClient.Delete().
+		Resource(c.resource).
+		Name(name).
+		Do(ctx).
+		Error()
+	if err != nil {
+		return nil, K8sErrorToCalico(err, key)
+	}
+	return kvp, nil
+}
+
+func (c *customK8sResourceClient) Get(ctx context.Context, key model.Key, revision string) (*model.KVPair, error) {
+	name, err := c.keyToName(key)
+	if err != nil {
+		return nil, err
+	}
+
+	out := reflect.New(c.k8sResourceType).Interface().(Resource)
+	err = c.restClient.Get().
+		Resource(c.resource).
+		Name(name).
+		Do(ctx).
+		Into(out)
+	if err != nil {
+		return nil, K8sErrorToCalico(err, key)
+	}
+	if c.options.Projection != ProjectMetadataOnly {
+		if err := ConvertK8sResourceToCalicoResource(out); err != nil {
+			return nil, err
+		}
+	}
+	return c.convertResourceToKVPairProjected(out)
+}
+
+func (c *customK8sResourceClient) List(ctx context.Context, list model.ListInterface, revision string) (*model.KVPairList, error) {
+	if key := c.listInterfaceToKey(list); key != nil {
+		kvp, err := c.Get(ctx, key, revision)
+		if err != nil {
+			if _, ok := err.(cerrors.ErrorResourceDoesNotExist); ok {
+				return &model.KVPairList{KVPairs: []*model.KVPair{}, Revision: revision}, nil
+			}
+			return nil, err
+		}
+		return &model.KVPairList{KVPairs: []*model.KVPair{kvp}, Revision: kvp.Revision}, nil
+	}
+
+	out := reflect.New(c.k8sListType).Interface().(ResourceList)
+	err := c.restClient.Get().
+		Resource(c.resource).
+		Do(ctx).
+		Into(out)
+	if err != nil {
+		return nil, K8sErrorToCalico(err, list)
+	}
+
+	items, err := meta.ExtractList(out)
+	if err != nil {
+		return nil, err
+	}
+	kvps := make([]*model.KVPair, 0, len(items))
+	for _, item := range items {
+		r, ok := item.(Resource)
+		if !ok {
+			continue
+		}
+		if c.options.Projection != ProjectMetadataOnly {
+			if err := ConvertK8sResourceToCalicoResource(r); err != nil {
+				return nil, err
+			}
+		}
+		kvp, err := c.convertResourceToKVPairProjected(r)
+		if err != nil {
+			return nil, err
+		}
+		kvps = append(kvps, kvp)
+	}
+
+	return &model.KVPairList{KVPairs: kvps, Revision: revision}, nil
+}
+
+func (c *customK8sResourceClient) Watch(ctx context.Context, list model.ListInterface, revision string) (api.WatchInterface, error) {
+	w, err := c.restClient.Get().
+		Resource(c.resource).
+		Param("watch", "true").
+		Param("resourceVersion", revision).
+		Watch(ctx)
+	if err != nil {
+		return nil, K8sErrorToCalico(err, list)
+	}
+	return newK8sWatcherConverter(ctx, c, w), nil
+}
+
+func (c *customK8sResourceClient) EnsureInitialized() error {
+	return nil
+}
+
+// K8sErrorToCalico converts a Kubernetes API error into the equivalent error from the
+// libcalico-go errors package, so that callers of this client only ever need to handle
+// the backend-agnostic error types.
+func K8sErrorToCalico(err error, identifier interface{}) error {
+	if err == nil {
+		return nil
+	}
+	switch {
+	case kerrors.IsNotFound(err):
+		return cerrors.ErrorResourceDoesNotExist{Err: err, Identifier: identifier}
+	case kerrors.IsAlreadyExists(err):
+		return cerrors.ErrorResourceAlreadyExists{Err: err, Identifier: identifier}
+	case kerrors.IsConflict(err):
+		return cerrors.ErrorResourceUpdateConflict{Err: err, Identifier: identifier}
+	}
+	return cerrors.ErrorDatastoreError{Err: err, Identifier: identifier}
+}
+
+// k8sWatcherConverter adapts a raw Kubernetes watch.Interface, which emits the
+// Kubernetes-native resource type, into one that emits model.KVPairs built from the
+// resource-specific converter.
+type k8sWatcherConverter struct {
+	ctx      context.Context
+	client   *customK8sResourceClient
+	k8sWatch watch.Interface
+	resultC  chan api.WatchEvent
+}
+
+func newK8sWatcherConverter(ctx context.Context, client *customK8sResourceClient, k8sWatch watch.Interface) *k8sWatcherConverter {
+	wc := &k8sWatcherConverter{
+		ctx:      ctx,
+		client:   client,
+		k8sWatch: k8sWatch,
+		resultC:  make(chan api.WatchEvent),
+	}
+	go wc.processEvents()
+	return wc
+}
+
+func (wc *k8sWatcherConverter) processEvents() {
+	defer close(wc.resultC)
+	for {
+		select {
+		case <-wc.ctx.Done():
+			wc.k8sWatch.Stop()
+			return
+		case event, ok := <-wc.k8sWatch.ResultChan():
+			if !ok {
+				return
+			}
+			r, ok := event.Object.(Resource)
+			if !ok {
+				log.Warningf("Unexpected object type on watch channel: %T", event.Object)
+				continue
+			}
+			if wc.client.options.Projection != ProjectMetadataOnly {
+				if err := ConvertK8sResourceToCalicoResource(r); err != nil {
+					wc.resultC <- api.WatchEvent{Type: api.WatchError, Error: err}
+					continue
+				}
+			}
+			kvp, err := wc.client.convertResourceToKVPairProjected(r)
+			if err != nil {
+				wc.resultC <- api.WatchEvent{Type: api.WatchError, Error: err}
+				continue
+			}
+			wc.resultC <- api.WatchEvent{Type: api.WatchEventType(event.Type), New: kvp}
+		}
+	}
+}
+
+func (wc *k8sWatcherConverter) Stop() {
+	wc.k8sWatch.Stop()
+}
+
+func (wc *k8sWatcherConverter) ResultChan() <-chan api.WatchEvent {
+	return wc.resultC
+}
+
+func (wc *k8sWatcherConverter) HasTerminated() bool {
+	return false
+}
@@ -15,16 +15,65 @@
 package resources
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	apiv3 "github.com/projectcalico/api/pkg/apis/projectcalico/v3"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
 
 	"github.com/projectcalico/calico/libcalico-go/lib/backend/model"
 	"github.com/projectcalico/calico/libcalico-go/lib/net"
 )
 
+// fakeSecretGetter is an in-memory SecretGetter used to unit test secret resolution
+// without a real Kubernetes API.
+type fakeSecretGetter map[string]*corev1.Secret
+
+func (f fakeSecretGetter) Get(_ context.Context, name string, _ metav1.GetOptions) (*corev1.Secret, error) {
+	s, ok := f[name]
+	if !ok {
+		return nil, fmt.Errorf("secret %s not found", name)
+	}
+	return s, nil
+}
+
+// bgpPeerGroupVersion is the (test-only) GroupVersion used to build a real rest.Interface
+// against an httptest server, so that Patch/Apply -- which depend on concrete
+// rest.Request behaviour, not just an interface we can hand-mock -- can be exercised.
+var bgpPeerGroupVersion = schema.GroupVersion{Group: "crd.projectcalico.org", Version: "v1"}
+
+// newTestRESTClient builds a rest.Interface that talks to the given httptest.Server,
+// using a minimal scheme that only knows about BGPPeer/BGPPeerList.
+func newTestRESTClient(server *httptest.Server) rest.Interface {
+	s := runtime.NewScheme()
+	s.AddKnownTypes(bgpPeerGroupVersion, &apiv3.BGPPeer{}, &apiv3.BGPPeerList{})
+	metav1.AddToGroupVersion(s, bgpPeerGroupVersion)
+	codecs := serializer.NewCodecFactory(s)
+
+	gv := bgpPeerGroupVersion
+	cfg := &rest.Config{
+		Host: server.URL,
+		ContentConfig: rest.ContentConfig{
+			GroupVersion:         &gv,
+			NegotiatedSerializer: codecs.WithoutConversion(),
+		},
+	}
+	c, err := rest.RESTClientFor(cfg)
+	Expect(err).NotTo(HaveOccurred())
+	return c
+}
+
 var _ = Describe("Custom resource conversion methods (tested using BGPPeer)", func() {
 	// Create an empty client since we are only testing conversion functions.
 	client := NewBGPPeerClient(nil, nil).(*customK8sResourceClient)
@@ -224,4 +273,326 @@ var _ = Describe("Custom resource conversion methods (tested using BGPPeer)", fu
 		Expect(resConverted.(*apiv3.BGPPeer).ObjectMeta.Labels).NotTo(HaveKey("foo"))
 		Expect(resConverted.(*apiv3.BGPPeer).ObjectMeta.Labels).NotTo(HaveKey("foo2"))
 	})
+
+	It("should preserve labels and annotations under a client's OwnedMetadataPrefixes", func() {
+		res := &apiv3.BGPPeer{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       apiv3.KindBGPPeer,
+				APIVersion: apiv3.GroupVersionCurrent,
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name1,
+				ResourceVersion: "rv",
+				UID:             convertedUID,
+				Labels: map[string]string{
+					"foo":                   "bar",
+					"projectcalico.org/foo": "bar",
+					"example.com/foo":       "bar",
+				},
+				Annotations: map[string]string{
+					"foo":                   "bar",
+					"projectcalico.org/foo": "bar",
+					"example.com/foo":       "bar",
+				},
+			},
+			Spec: apiv3.BGPPeerSpec{},
+		}
+
+		// Rather than RegisterOwnedMetadataPrefix, which mutates the package-global
+		// ownedMetadataPrefixes for every client in the process, exercise a single
+		// client's own ClientOptions.OwnedMetadataPrefixes.
+		client := NewBGPPeerClientWithOptions(nil, nil, ClientOptions{
+			OwnedMetadataPrefixes: []string{"example.com/"},
+		}).(*customK8sResourceClient)
+
+		resConverted, err := convertCalicoResourceToK8sResourceForCluster(res, client.options.OwnedMetadataPrefixes, client.options.ClusterID)
+		Expect(err).NotTo(HaveOccurred())
+
+		// The client's registered prefix is owned and so is preserved, alongside the
+		// built-in projectcalico.org/ prefix. The bare "foo" key is still stripped.
+		Expect(resConverted.(*apiv3.BGPPeer).ObjectMeta.Labels).To(Equal(map[string]string{
+			"projectcalico.org/foo": "bar",
+			"example.com/foo":       "bar",
+		}))
+		Expect(resConverted.(*apiv3.BGPPeer).ObjectMeta.Annotations).To(HaveKeyWithValue("projectcalico.org/foo", "bar"))
+		Expect(resConverted.(*apiv3.BGPPeer).ObjectMeta.Annotations).To(HaveKeyWithValue("example.com/foo", "bar"))
+		Expect(resConverted.(*apiv3.BGPPeer).ObjectMeta.Annotations).NotTo(HaveKey("foo"))
+	})
+
+	It("should strip legacy unregistered prefixes when no extra prefix is registered", func() {
+		// A fresh client with no extra owned prefixes configured still only honours
+		// the built-in projectcalico.org/ and operator.tigera.io/ prefixes.
+		plain := NewBGPPeerClient(nil, nil).(*customK8sResourceClient)
+		Expect(plain.options.OwnedMetadataPrefixes).To(BeEmpty())
+
+		res := &apiv3.BGPPeer{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name1,
+				Labels: map[string]string{
+					"foo":                    "bar",
+					"projectcalico.org/foo":  "bar",
+					"operator.tigera.io/foo": "bar",
+				},
+			},
+			Spec: apiv3.BGPPeerSpec{},
+		}
+
+		resConverted, err := ConvertCalicoResourceToK8sResource(res)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resConverted.(*apiv3.BGPPeer).ObjectMeta.Labels).NotTo(HaveKey("foo"))
+		Expect(resConverted.(*apiv3.BGPPeer).ObjectMeta.Labels).To(HaveKeyWithValue("projectcalico.org/foo", "bar"))
+		Expect(resConverted.(*apiv3.BGPPeer).ObjectMeta.Labels).To(HaveKeyWithValue("operator.tigera.io/foo", "bar"))
+	})
+
+	It("should convert a resource into a metadata-only KVPair when projecting", func() {
+		res := &apiv3.BGPPeer{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       apiv3.KindBGPPeer,
+				APIVersion: apiv3.GroupVersionCurrent,
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name1,
+				ResourceVersion: "rv",
+				UID:             convertedUID,
+				Labels: map[string]string{
+					"foo":                    "bar",
+					"projectcalico.org/foo":  "bar",
+					"operator.tigera.io/foo": "bar",
+				},
+			},
+			Spec: apiv3.BGPPeerSpec{
+				PeerIP:   peerIP1.String(),
+				ASNumber: 1212,
+			},
+		}
+
+		projectedClient := NewBGPPeerClientWithOptions(nil, nil, ClientOptions{
+			Projection: ProjectMetadataOnly,
+		}).(*customK8sResourceClient)
+
+		kvp, err := projectedClient.convertResourceToKVPairProjected(res)
+		Expect(err).NotTo(HaveOccurred())
+
+		partial, ok := kvp.Value.(*metav1.PartialObjectMetadata)
+		Expect(ok).To(BeTrue(), "expected a *metav1.PartialObjectMetadata, got %T", kvp.Value)
+		Expect(partial.Name).To(Equal(name1))
+		Expect(partial.ResourceVersion).To(Equal("rv"))
+
+		// Unlike the write path, reading never strips anything: Kubernetes is the
+		// source of truth for labels/annotations it already carries, and a
+		// metadata-only List/Watch must return the same set a full one would.
+		Expect(partial.Labels).To(HaveKeyWithValue("foo", "bar"))
+		Expect(partial.Labels).To(HaveKeyWithValue("projectcalico.org/foo", "bar"))
+		Expect(partial.Labels).To(HaveKeyWithValue("operator.tigera.io/foo", "bar"))
+
+		// The full-projection client, by contrast, still returns the full resource.
+		fullClient := NewBGPPeerClient(nil, nil).(*customK8sResourceClient)
+		kvp, err = fullClient.convertResourceToKVPairProjected(res)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kvp.Value).To(BeAssignableToTypeOf(&apiv3.BGPPeer{}))
+	})
+
+	It("should resolve a BGPPeer's secret-backed password without leaking it into the on-disk CR", func() {
+		secrets := fakeSecretGetter{
+			"bgp-secrets": &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "bgp-secrets"},
+				Data:       map[string][]byte{"password": []byte("s3cr3t")},
+			},
+		}
+
+		peer := &apiv3.BGPPeer{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       apiv3.KindBGPPeer,
+				APIVersion: apiv3.GroupVersionCurrent,
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name1,
+				ResourceVersion: "rv",
+			},
+			Spec: apiv3.BGPPeerSpec{
+				PeerIP: peerIP1.String(),
+				Password: &apiv3.BGPPassword{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "bgp-secrets"},
+						Key:                  "password",
+					},
+				},
+			},
+		}
+
+		// Writing the resource (v3 -> k8s) must stamp the secret-refs annotation,
+		// and must never write the plaintext password anywhere on the object.
+		resConverted, err := ConvertCalicoResourceToK8sResource(peer)
+		Expect(err).NotTo(HaveOccurred())
+		onDisk := resConverted.(*apiv3.BGPPeer)
+		Expect(onDisk.ObjectMeta.Annotations).To(HaveKeyWithValue(BGPPeerSecretRefsAnnotation, "password=bgp-secrets/password"))
+
+		serialized := fmt.Sprintf("%#v", onDisk)
+		Expect(serialized).NotTo(ContainSubstring("s3cr3t"))
+
+		// Reading it back (k8s -> v3) should restore the SecretKeyRef from the
+		// annotation if it were ever missing from Spec.
+		onDisk.Spec.Password = nil
+		err = ConvertK8sResourceToCalicoResource(onDisk)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(onDisk.Spec.Password).NotTo(BeNil())
+		Expect(onDisk.Spec.Password.SecretKeyRef.Name).To(Equal("bgp-secrets"))
+		Expect(onDisk.Spec.Password.SecretKeyRef.Key).To(Equal("password"))
+
+		// A caller with a SecretGetter configured can get the plaintext back out, on
+		// demand, without it ever having been persisted.
+		password, err := ResolveBGPPeerPassword(context.Background(), onDisk, secrets)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(password).To(Equal("s3cr3t"))
+	})
+
+	It("should preserve ManagedFields across v3 <-> k8s conversion", func() {
+		managedFields := []metav1.ManagedFieldsEntry{
+			{
+				Manager:     "crossplane-provider-kubernetes",
+				Operation:   metav1.ManagedFieldsOperationApply,
+				APIVersion:  apiv3.GroupVersionCurrent,
+				FieldsType:  "FieldsV1",
+				FieldsV1:    &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:peerIP":{}}}`)},
+				Subresource: "",
+			},
+		}
+
+		res := &apiv3.BGPPeer{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       apiv3.KindBGPPeer,
+				APIVersion: apiv3.GroupVersionCurrent,
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:          name1,
+				ManagedFields: managedFields,
+			},
+			Spec: apiv3.BGPPeerSpec{PeerIP: peerIP1.String()},
+		}
+
+		resConverted, err := ConvertCalicoResourceToK8sResource(res)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resConverted.(*apiv3.BGPPeer).ObjectMeta.ManagedFields).To(Equal(managedFields))
+
+		err = ConvertK8sResourceToCalicoResource(resConverted)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resConverted.(*apiv3.BGPPeer).ObjectMeta.ManagedFields).To(HaveLen(1))
+		Expect(resConverted.(*apiv3.BGPPeer).ObjectMeta.ManagedFields[0].Manager).To(Equal("crossplane-provider-kubernetes"))
+		Expect(resConverted.(*apiv3.BGPPeer).ObjectMeta.ManagedFields[0].FieldsV1.Raw).To(Equal([]byte(`{"f:spec":{"f:peerIP":{}}}`)))
+		Expect(resConverted.(*apiv3.BGPPeer).ObjectMeta.ManagedFields[0].Subresource).To(Equal(""))
+	})
+
+	It("should preserve a replicated resource's source UID across clusters", func() {
+		sourceUID := types.UID("source-cluster-a-uid")
+		res := &apiv3.BGPPeer{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       apiv3.KindBGPPeer,
+				APIVersion: apiv3.GroupVersionCurrent,
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name1,
+				ResourceVersion: "5",
+				UID:             sourceUID,
+			},
+			Spec: apiv3.BGPPeerSpec{PeerIP: peerIP1.String()},
+		}
+
+		// Writing this resource from cluster A's client stamps the source-* annotations.
+		resConverted, err := convertCalicoResourceToK8sResourceForCluster(res, nil, "cluster-a")
+		Expect(err).NotTo(HaveOccurred())
+		onDisk := resConverted.(*apiv3.BGPPeer)
+		Expect(onDisk.ObjectMeta.Annotations).To(HaveKeyWithValue("projectcalico.org/source-cluster", "cluster-a"))
+		Expect(onDisk.ObjectMeta.Annotations).To(HaveKeyWithValue("projectcalico.org/source-uid", string(sourceUID)))
+		Expect(onDisk.ObjectMeta.Annotations).To(HaveKeyWithValue("projectcalico.org/source-resource-version", "5"))
+
+		// Cluster B's API server assigns its own local UID on write.
+		onDisk.ObjectMeta.UID = types.UID("cluster-b-local-uid")
+
+		// Reading it back reconstructs the stable identity from cluster A, not the
+		// locally-assigned UID.
+		err = ConvertK8sResourceToCalicoResource(onDisk)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(onDisk.ObjectMeta.UID).To(Equal(sourceUID))
+	})
+
+	It("should preserve a replicated resource's source UID in the metadata-only projection", func() {
+		sourceUID := types.UID("source-cluster-a-uid")
+		res := &apiv3.BGPPeer{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       apiv3.KindBGPPeer,
+				APIVersion: apiv3.GroupVersionCurrent,
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name1,
+				ResourceVersion: "5",
+				UID:             types.UID("cluster-b-local-uid"),
+				Annotations: map[string]string{
+					"projectcalico.org/source-cluster":          "cluster-a",
+					"projectcalico.org/source-uid":              string(sourceUID),
+					"projectcalico.org/source-resource-version": "5",
+				},
+			},
+			Spec: apiv3.BGPPeerSpec{PeerIP: peerIP1.String()},
+		}
+
+		// A GC loop or other metadata-only consumer must see the same reconstructed
+		// source UID as a full-projection client would, even though it never calls
+		// ConvertK8sResourceToCalicoResource.
+		projectedClient := NewBGPPeerClientWithOptions(nil, nil, ClientOptions{
+			Projection: ProjectMetadataOnly,
+		}).(*customK8sResourceClient)
+
+		kvp, err := projectedClient.convertResourceToKVPairProjected(res)
+		Expect(err).NotTo(HaveOccurred())
+		partial, ok := kvp.Value.(*metav1.PartialObjectMetadata)
+		Expect(ok).To(BeTrue(), "expected a *metav1.PartialObjectMetadata, got %T", kvp.Value)
+		Expect(partial.UID).To(Equal(sourceUID))
+	})
+
+	It("should send a managedFields-free, apply-flagged PATCH for Apply", func() {
+		var (
+			gotMethod       string
+			gotForceParam   string
+			gotFieldManager string
+			gotBody         []byte
+		)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotMethod = r.Method
+			gotForceParam = r.URL.Query().Get("force")
+			gotFieldManager = r.URL.Query().Get("fieldManager")
+			gotBody, _ = io.ReadAll(r.Body)
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(gotBody)
+		}))
+		defer server.Close()
+
+		client := NewBGPPeerClient(nil, newTestRESTClient(server)).(*customK8sResourceClient)
+
+		peer := &apiv3.BGPPeer{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       apiv3.KindBGPPeer,
+				APIVersion: apiv3.GroupVersionCurrent,
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name1,
+				ManagedFields: []metav1.ManagedFieldsEntry{
+					{Manager: "some-other-controller"},
+				},
+			},
+			Spec: apiv3.BGPPeerSpec{PeerIP: peerIP1.String()},
+		}
+		kvp := &model.KVPair{Key: key1, Value: peer}
+
+		_, err := client.Apply(context.Background(), kvp, "my-field-manager", true)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(gotMethod).To(Equal(http.MethodPatch))
+		Expect(gotForceParam).To(Equal("true"))
+		Expect(gotFieldManager).To(Equal("my-field-manager"))
+		Expect(string(gotBody)).NotTo(ContainSubstring("managedFields"))
+		Expect(string(gotBody)).To(ContainSubstring(`"kind":"BGPPeer"`))
+	})
 })